@@ -0,0 +1,137 @@
+package gelf
+
+import (
+	"errors"
+	"time"
+)
+
+// Stats is a snapshot of the delivery counters tracked by a Gelf writer
+type Stats struct {
+	// Sent is the number of messages successfully delivered, whether sent inline by
+	// Write or by the async background worker
+	Sent uint64
+	// Dropped is the number of messages discarded because the async queue was full;
+	// always zero unless Config.Async is set
+	Dropped uint64
+	// Failed is the number of messages that were not successfully delivered
+	Failed uint64
+	// Reconnects is the number of times the TCP transport has had to redial;
+	// always zero unless Config.Transport is TransportTCP
+	Reconnects uint64
+}
+
+// Stats returns a snapshot of this writer's delivery counters.
+func (g *Gelf) Stats() Stats {
+	return Stats{
+		Sent:       g.sent.Load(),
+		Dropped:    g.dropped.Load(),
+		Failed:     g.failed.Load(),
+		Reconnects: g.reconnects.Load(),
+	}
+}
+
+// writeAsync hands p to the background send queue, applying Config.OverflowStrategy
+// if the queue is full, and returns without compressing or sending inline; only
+// OverflowBlock makes it wait, until the queue has room
+func (g *Gelf) writeAsync(p []byte) (int, error) {
+	payload := make([]byte, len(p))
+	copy(payload, p)
+
+	select {
+	case g.queue <- payload:
+		return len(p), nil
+	default:
+	}
+
+	switch g.Config.OverflowStrategy {
+	case OverflowDropOldest:
+		select {
+		case <-g.queue:
+			g.dropped.Add(1)
+		default:
+		}
+
+		select {
+		case g.queue <- payload:
+		default:
+			g.dropped.Add(1)
+		}
+
+	case OverflowDropNewest:
+		g.dropped.Add(1)
+
+	default: // OverflowBlock
+		g.queue <- payload
+	}
+
+	return len(p), nil
+}
+
+// asyncLoop drains the queue on a background goroutine, sending each message with
+// writeSync and tallying the result, until Close shuts the queue down
+func (g *Gelf) asyncLoop() {
+	defer g.wg.Done()
+
+	for {
+		select {
+		case payload := <-g.queue:
+			g.sendQueued(payload)
+
+		case <-g.closed:
+			g.drainQueue()
+			return
+		}
+	}
+}
+
+// drainQueue flushes any messages left in the queue without blocking for more
+func (g *Gelf) drainQueue() {
+	for {
+		select {
+		case payload := <-g.queue:
+			g.sendQueued(payload)
+		default:
+			return
+		}
+	}
+}
+
+// sendQueued sends a single queued payload and records the outcome in Stats
+func (g *Gelf) sendQueued(payload []byte) {
+	g.writeSyncAndRecord(payload)
+}
+
+// Close stops this writer's background goroutines (the async worker, if Config.Async is
+// set, and the UDP address watcher), draining any queued async messages first, and waits
+// up to timeout for them to finish. Calling Close more than once is safe; only the first
+// call has any effect.
+func (g *Gelf) Close(timeout time.Duration) error {
+	var err error
+
+	g.closeOnce.Do(func() {
+		close(g.closed)
+
+		done := make(chan struct{})
+		go func() {
+			g.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			err = errors.New("gelf: timed out waiting for background goroutines to stop")
+		}
+
+		g.mu.Lock()
+		if g.udpConn != nil {
+			g.udpConn.Close()
+		}
+		if g.tcpConn != nil {
+			g.tcpConn.Close()
+		}
+		g.mu.Unlock()
+	})
+
+	return err
+}
@@ -0,0 +1,107 @@
+package gelf
+
+import "time"
+
+// Version is the GELF specification version stamped onto messages built by callers of this writer
+const Version = "1.1"
+
+// DefaultMaxChunkSize is the compressed payload size, in bytes, above which a message is split into chunks
+const DefaultMaxChunkSize = 8154
+
+// DefaultPort is the GELF UDP/TCP input port used by Graylog out of the box
+const DefaultPort = 12201
+
+// DefaultMaxReconnect is the number of reconnect attempts a TCP transport makes before giving up on a Write
+const DefaultMaxReconnect = 3
+
+// DefaultReconnectDelay is the pause between TCP reconnect attempts
+const DefaultReconnectDelay = time.Second
+
+// DefaultQueueSize is the number of messages buffered between Write and the async send loop
+const DefaultQueueSize = 1024
+
+// Compression selects the payload compression algorithm applied before transmission
+type Compression int
+
+const (
+	// CompressionNone sends the payload uncompressed
+	CompressionNone Compression = iota
+	// CompressionGZip compresses the payload using gzip
+	CompressionGZip
+	// CompressionZLib compresses the payload using zlib
+	CompressionZLib
+)
+
+// Transport selects the network protocol used to deliver GELF messages
+type Transport int
+
+const (
+	// TransportUDP sends messages as (optionally chunked) UDP datagrams
+	TransportUDP Transport = iota
+	// TransportTCP sends messages over a persistent TCP stream, each terminated with \x00;
+	// the message bytes themselves are sent unchanged, including any newlines
+	TransportTCP
+)
+
+// OverflowStrategy controls how an async Gelf behaves when its queue is full
+type OverflowStrategy int
+
+const (
+	// OverflowBlock makes Write block until the queue has room, applying back-pressure
+	OverflowBlock OverflowStrategy = iota
+	// OverflowDropNewest discards the message passed to Write, keeping the queue as-is
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued message to make room for the new one
+	OverflowDropOldest
+)
+
+// Config holds connection and behaviour options for a Gelf WriteSyncer
+type Config struct {
+	// Host is the Graylog (or other GELF input) hostname or IP address
+	Host string
+	// Port is the GELF input port, see DefaultPort
+	Port int
+
+	// Transport selects between UDP (chunked, default) and TCP
+	Transport Transport
+
+	// MaxReconnect bounds how many times a TCP transport redials after a failed Write
+	MaxReconnect int
+	// ReconnectDelay is the pause between TCP reconnect attempts
+	ReconnectDelay time.Duration
+
+	// Compression selects the payload compression algorithm; it applies to both UDP and TCP
+	// transports (TCP only disables chunking)
+	Compression Compression
+	// MaxChunkSize is the compressed payload size, in bytes, above which a UDP message is chunked;
+	// unused for TransportTCP, which never chunks. Acts as an upper bound when DiscoverMTU is set.
+	MaxChunkSize int
+	// DiscoverMTU, when true, probes the path MTU to the destination in New, and again each time
+	// the UDP destination is re-dialed, and lowers MaxChunkSize to fit it, so UDP datagrams aren't fragmented by an unexpectedly small MTU
+	// (e.g. a VPN or tunnel). It never raises MaxChunkSize above what was explicitly configured.
+	DiscoverMTU bool
+
+	// Async, when true, makes Write hand the payload to a background goroutine and return
+	// instead of compressing and sending inline on the caller's goroutine; Write returns
+	// immediately unless the queue is full and OverflowStrategy is OverflowBlock
+	Async bool
+	// QueueSize bounds the number of messages buffered between Write and the async send loop,
+	// see DefaultQueueSize; ignored unless Async is true
+	QueueSize int
+	// OverflowStrategy controls what happens when the async queue is full; ignored unless Async is true
+	OverflowStrategy OverflowStrategy
+}
+
+// DefaultConfig returns a Config with sensible defaults for the given GELF host, sending
+// uncompressed, unchunked-unless-needed UDP datagrams to the standard Graylog port
+func DefaultConfig(host string) Config {
+	return Config{
+		Host:           host,
+		Port:           DefaultPort,
+		Transport:      TransportUDP,
+		MaxReconnect:   DefaultMaxReconnect,
+		ReconnectDelay: DefaultReconnectDelay,
+		Compression:    CompressionNone,
+		MaxChunkSize:   DefaultMaxChunkSize,
+	}
+}
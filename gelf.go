@@ -5,20 +5,100 @@ import (
 	"compress/gzip"
 	"compress/zlib"
 	"crypto/rand"
-	"encoding/binary"
+	"fmt"
 	"math"
 	"net"
-	"strconv"
+	"sync"
+	"sync/atomic"
 )
 
+const (
+	// gelfMagicByte1 and gelfMagicByte2 identify a chunked GELF packet on the wire
+	gelfMagicByte1 = 0x1e
+	gelfMagicByte2 = 0x0f
+
+	// chunkHeaderSize is the fixed size, in bytes, of a chunked GELF packet header:
+	// 2 magic bytes + 8-byte message ID + 1-byte sequence number + 1-byte sequence count
+	chunkHeaderSize = 12
+
+	// maxChunks is the largest sequence count the GELF chunking format allows for one message
+	maxChunks = 128
+)
+
+// bufferPool recycles the scratch buffers used for compressed payloads and chunk headers,
+// avoiding a fresh allocation on every Write
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// gzipWriterPool recycles gzip.Writers across Writes via Reset, avoiding their internal
+// allocations on every call
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// zlibWriterPool recycles zlib.Writers across Writes via Reset, avoiding their internal
+// allocations on every call
+var zlibWriterPool = sync.Pool{
+	New: func() interface{} {
+		return zlib.NewWriter(nil)
+	},
+}
+
 // New sets up a new instance of Gelf ready for zap
 func New(config Config) *Gelf {
-	return &Gelf{Config: config}
+	g := &Gelf{
+		Config: config,
+		closed: make(chan struct{}),
+	}
+
+	if config.Transport == TransportUDP {
+		g.udpConn, _ = g.dialUDP()
+
+		if g.udpConn != nil && config.DiscoverMTU {
+			_ = enablePathMTUDiscovery(g.udpConn)
+			g.applyChunkSize()
+		}
+
+		g.wg.Add(1)
+		go g.watchUDPAddr()
+	}
+
+	if config.Async {
+		queueSize := config.QueueSize
+		if queueSize <= 0 {
+			queueSize = DefaultQueueSize
+		}
+
+		g.queue = make(chan []byte, queueSize)
+		g.wg.Add(1)
+		go g.asyncLoop()
+	}
+
+	return g
 }
 
 // Gelf is an operational structure which holds the implementation of WriteSyncer
 type Gelf struct {
 	Config
+
+	mu      sync.Mutex
+	udpConn *net.UDPConn
+	tcpConn net.Conn
+
+	queue     chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	sent       atomic.Uint64
+	dropped    atomic.Uint64
+	failed     atomic.Uint64
+	reconnects atomic.Uint64
 }
 
 // Sync implements the WriteSyncer Sync method, nothing to do here so just noop
@@ -26,10 +106,33 @@ func (g *Gelf) Sync() error {
 	return nil
 }
 
-// Write implements a Writer Write method
+// Write implements a Writer Write method, handing off to the background queue when
+// Config.Async is set and sending inline otherwise
 func (g *Gelf) Write(p []byte) (int, error) {
+	if g.Config.Async {
+		return g.writeAsync(p)
+	}
+
+	return g.writeSyncAndRecord(p)
+}
+
+// writeSyncAndRecord sends p inline via writeSync and tallies the outcome in Stats,
+// shared by the synchronous Write path and the async worker
+func (g *Gelf) writeSyncAndRecord(p []byte) (int, error) {
+	n, err := g.writeSync(p)
+	if err != nil {
+		g.failed.Add(1)
+	} else {
+		g.sent.Add(1)
+	}
+
+	return n, err
+}
+
+// writeSync compresses and sends p on the calling goroutine
+func (g *Gelf) writeSync(p []byte) (int, error) {
 	var (
-		buf bytes.Buffer
+		buf *bytes.Buffer
 		err error
 	)
 
@@ -47,19 +150,43 @@ func (g *Gelf) Write(p []byte) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	defer bufferPool.Put(buf)
 
+	if g.Config.Transport == TransportTCP {
+		if _, err := g.sendTCP(buf.Bytes()); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	g.mu.Lock()
 	chunksize := g.Config.MaxChunkSize
+	g.mu.Unlock()
+
 	length := buf.Len()
 
 	if length > chunksize {
-		chunkCountInt := int(math.Ceil(float64(length) / float64(chunksize)))
+		if chunksize <= chunkHeaderSize {
+			return 0, fmt.Errorf("gelf: MaxChunkSize %d must be greater than the %d-byte chunk header", chunksize, chunkHeaderSize)
+		}
+
+		payloadSize := chunksize - chunkHeaderSize
+		chunkCountInt := int(math.Ceil(float64(length) / float64(payloadSize)))
+
+		if chunkCountInt > maxChunks {
+			return 0, fmt.Errorf("gelf: compressed message needs %d chunks, exceeding the GELF limit of %d", chunkCountInt, maxChunks)
+		}
 
 		id := make([]byte, 8)
-		rand.Read(id)
+		if _, err := rand.Read(id); err != nil {
+			return 0, err
+		}
 
-		for i, index := 0, 0; i < length; i, index = i+chunksize, index+1 {
-			packet := g.createChunkedMessage(index, chunkCountInt, id, &buf)
+		for i, index := 0, 0; i < length; i, index = i+payloadSize, index+1 {
+			packet := g.createChunkedMessage(index, chunkCountInt, id, buf, payloadSize)
 			_, err := g.send(packet.Bytes())
+			bufferPool.Put(packet)
 			if err != nil {
 				return 0, err
 			}
@@ -74,94 +201,79 @@ func (g *Gelf) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
-// createChunkedMessage creates UDP packets for transmission using the chunk size
-func (g *Gelf) createChunkedMessage(index int, chunkCountInt int, id []byte, compressed *bytes.Buffer) bytes.Buffer {
-	var packet bytes.Buffer
-
-	chunksize := g.Config.MaxChunkSize
+// createChunkedMessage builds a UDP chunk packet from a pooled buffer, taking the next
+// payloadSize bytes (the chunk size minus the header) of the compressed message
+func (g *Gelf) createChunkedMessage(index int, chunkCountInt int, id []byte, compressed *bytes.Buffer, payloadSize int) *bytes.Buffer {
+	packet := bufferPool.Get().(*bytes.Buffer)
+	packet.Reset()
 
-	packet.Write(g.intToBytes(30))
-	packet.Write(g.intToBytes(15))
+	packet.WriteByte(gelfMagicByte1)
+	packet.WriteByte(gelfMagicByte2)
 	packet.Write(id)
 
-	packet.Write(g.intToBytes(index))
-	packet.Write(g.intToBytes(chunkCountInt))
+	packet.WriteByte(byte(index))
+	packet.WriteByte(byte(chunkCountInt))
 
-	packet.Write(compressed.Next(chunksize))
+	packet.Write(compressed.Next(payloadSize))
 
 	return packet
 }
 
-// intToBytes writes numbers into a byte message using LittleEndian
-func (g *Gelf) intToBytes(i int) []byte {
-	buf := new(bytes.Buffer)
-
-	binary.Write(buf, binary.LittleEndian, int8(i))
-
-	return buf.Bytes()
-}
-
-// compressNone just returns the message as a bytes.Buffer
-func (g *Gelf) compressNone(b []byte) (bytes.Buffer, error) {
-	var buf bytes.Buffer
+// compressNone just returns the message as a pooled bytes.Buffer
+func (g *Gelf) compressNone(b []byte) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
 
-	_, err := buf.Write(b)
-	if err != nil {
-		return buf, err
+	if _, err := buf.Write(b); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
 	}
 
 	return buf, nil
 }
 
-// compressGZip squashes the buffer using gzip compression
-func (g *Gelf) compressGZip(b []byte) (bytes.Buffer, error) {
-	var buf bytes.Buffer
-	comp := gzip.NewWriter(&buf)
+// compressGZip squashes the buffer using a pooled gzip.Writer
+func (g *Gelf) compressGZip(b []byte) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
 
-	_, err := comp.Write(b)
-	if err != nil {
-		return buf, err
+	comp := gzipWriterPool.Get().(*gzip.Writer)
+	comp.Reset(buf)
+	defer gzipWriterPool.Put(comp)
+
+	if _, err := comp.Write(b); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
 	}
 
 	if err := comp.Close(); err != nil {
-		return buf, err
+		bufferPool.Put(buf)
+		return nil, err
 	}
 
 	return buf, nil
 }
 
-// compressZLib squashes the buffer using zlib compression
-func (g *Gelf) compressZLib(b []byte) (bytes.Buffer, error) {
-	var buf bytes.Buffer
-	comp := zlib.NewWriter(&buf)
+// compressZLib squashes the buffer using a pooled zlib.Writer
+func (g *Gelf) compressZLib(b []byte) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
 
-	_, err := comp.Write(b)
-	if err != nil {
-		return buf, err
+	comp := zlibWriterPool.Get().(*zlib.Writer)
+	comp.Reset(buf)
+	defer zlibWriterPool.Put(comp)
+
+	if _, err := comp.Write(b); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
 	}
 
 	if err := comp.Close(); err != nil {
-		return buf, err
+		bufferPool.Put(buf)
+		return nil, err
 	}
 
 	return buf, nil
 }
 
-// send transmits a packet
-func (g *Gelf) send(b []byte) (int, error) {
-	var addr = g.Config.Host + ":" + strconv.Itoa(g.Config.Port)
-
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		return 0, err
-	}
-
-	conn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		return 0, err
-	}
-
-	return conn.Write(b)
-}
-
 // EOF
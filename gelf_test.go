@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"testing"
+	"time"
 )
 
 var (
@@ -261,6 +262,15 @@ func TestGELFchunked(t *testing.T) {
 		t.Fatalf("Read GELF packet two failed: %s", readErr.Error())
 	}
 
+	// Wire size must never exceed MaxChunkSize, header included
+	if readLenOne > config.MaxChunkSize {
+		t.Errorf("Packet One wire size (%d) exceeds MaxChunkSize (%d)", readLenOne, config.MaxChunkSize)
+	}
+
+	if readLenTwo > config.MaxChunkSize {
+		t.Errorf("Packet Two wire size (%d) exceeds MaxChunkSize (%d)", readLenTwo, config.MaxChunkSize)
+	}
+
 	packet := packetOne[12:readLenOne]
 	packet = append(packet, packetTwo[12:readLenTwo]...)
 
@@ -324,4 +334,358 @@ func TestGELFchunked(t *testing.T) {
 	}
 }
 
+// TestGELFTCP
+func TestGELFTCP(t *testing.T) {
+	host := "host.example.org"
+	msg := "TCP Test Message"
+	packet := make([]byte, 2048)
+
+	testJSON, err := testMessage(msg, host)
+	if err != nil {
+		t.Fatalf("Getting Test Message failed: %s", err.Error())
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("TCP Listener failed: %s", err.Error())
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	config := DefaultConfig("127.0.0.1")
+	config.Port = ln.Addr().(*net.TCPAddr).Port
+	config.Transport = TransportTCP
+
+	gelf := New(config)
+	defer gelf.Close(time.Second)
+
+	sendLen, sendErr := gelf.Write(testJSON)
+	if sendErr != nil {
+		t.Fatalf("Send GELF Message failed: %s", sendErr.Error())
+	}
+
+	var conn net.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("TCP server never received a connection")
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	readLen, readErr := conn.Read(packet)
+	if readErr != nil {
+		t.Fatalf("Read GELF Message failed: %s", readErr.Error())
+	}
+
+	if packet[readLen-1] != 0x00 {
+		t.Errorf("TCP message not terminated with \\x00, last byte was %#x", packet[readLen-1])
+	}
+
+	var receivedData testMsg
+	if err := json.Unmarshal(packet[:readLen-1], &receivedData); err != nil {
+		t.Fatalf("JSON unmarshal failed: %s", err.Error())
+	}
+
+	if receivedData.Host != host {
+		t.Errorf("Sent Host (%s) does not equal Received Host (%s)", host, receivedData.Host)
+	}
+
+	if receivedData.Message != msg {
+		t.Errorf("Sent Message (%s) does not equal Received Message (%s)", msg, receivedData.Message)
+	}
+
+	if sendLen != len(testJSON) {
+		t.Errorf("Send Length (%d) did not equal JSON Length (%d)", sendLen, len(testJSON))
+	}
+}
+
+// TestGELFTCPReconnect
+func TestGELFTCPReconnect(t *testing.T) {
+	host := "host.example.org"
+	packet := make([]byte, 2048)
+
+	firstJSON, err := testMessage("first", host)
+	if err != nil {
+		t.Fatalf("Getting Test Message failed: %s", err.Error())
+	}
+
+	secondJSON, err := testMessage("second", host)
+	if err != nil {
+		t.Fatalf("Getting Test Message failed: %s", err.Error())
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("TCP Listener failed: %s", err.Error())
+	}
+	defer ln.Close()
+
+	config := DefaultConfig("127.0.0.1")
+	config.Port = ln.Addr().(*net.TCPAddr).Port
+	config.Transport = TransportTCP
+	config.MaxReconnect = 2
+	config.ReconnectDelay = 10 * time.Millisecond
+
+	gelf := New(config)
+	defer gelf.Close(time.Second)
+
+	firstAccept := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			firstAccept <- conn
+		}
+	}()
+
+	if _, err := gelf.Write(firstJSON); err != nil {
+		t.Fatalf("Send first GELF Message failed: %s", err.Error())
+	}
+
+	var firstConn net.Conn
+	select {
+	case firstConn = <-firstAccept:
+	case <-time.After(time.Second):
+		t.Fatal("TCP server never received the first connection")
+	}
+	firstConn.Close() // simulate a dropped connection, forcing a reconnect
+
+	secondAccept := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			secondAccept <- conn
+		}
+	}()
+
+	if _, err := gelf.Write(secondJSON); err != nil {
+		t.Fatalf("Send second GELF Message failed: %s", err.Error())
+	}
+
+	var secondConn net.Conn
+	select {
+	case secondConn = <-secondAccept:
+	case <-time.After(time.Second):
+		t.Fatal("TCP server never reconnected")
+	}
+	defer secondConn.Close()
+
+	secondConn.SetReadDeadline(time.Now().Add(time.Second))
+	readLen, readErr := secondConn.Read(packet)
+	if readErr != nil {
+		t.Fatalf("Read GELF Message failed: %s", readErr.Error())
+	}
+
+	if !bytes.Contains(packet[:readLen], []byte("second")) {
+		t.Errorf("Reconnected TCP connection did not receive the post-reconnect message, got %q", packet[:readLen])
+	}
+
+	if stats := gelf.Stats(); stats.Reconnects != 1 {
+		t.Errorf("Stats().Reconnects = %d, want 1", stats.Reconnects)
+	}
+}
+
+// TestGELFAsync
+func TestGELFAsync(t *testing.T) {
+	host := "host.example.org"
+	msg := "Async Test Message"
+	packet := make([]byte, 2048)
+
+	testJSON, err := testMessage(msg, host)
+	if err != nil {
+		t.Fatalf("Getting Test Message failed: %s", err.Error())
+	}
+
+	config := DefaultConfig("127.0.0.1")
+	config.Port = 1234
+	config.Async = true
+	config.QueueSize = 4
+
+	gelf := New(config)
+
+	sendLen, sendErr := gelf.Write(testJSON)
+	if sendErr != nil {
+		t.Fatalf("Send GELF Message failed: %s", sendErr.Error())
+	}
+
+	if sendLen != len(testJSON) {
+		t.Errorf("Send Length (%d) did not equal JSON Length (%d)", sendLen, len(testJSON))
+	}
+
+	if closeErr := gelf.Close(time.Second); closeErr != nil {
+		t.Fatalf("Close failed: %s", closeErr.Error())
+	}
+
+	testUDPlistener.SetReadDeadline(time.Now().Add(time.Second))
+	readLen, _, readErr := testUDPlistener.ReadFromUDP(packet)
+	testUDPlistener.SetReadDeadline(time.Time{})
+	if readErr != nil {
+		t.Fatalf("Read GELF Message failed: %s", readErr.Error())
+	}
+
+	var receivedData testMsg
+	if err := json.Unmarshal(packet[0:readLen], &receivedData); err != nil {
+		t.Fatalf("JSON unmarshal failed: %s", err.Error())
+	}
+
+	if receivedData.Message != msg {
+		t.Errorf("Sent Message (%s) does not equal Received Message (%s)", msg, receivedData.Message)
+	}
+
+	if stats := gelf.Stats(); stats.Sent != 1 {
+		t.Errorf("Stats().Sent = %d, want 1", stats.Sent)
+	}
+}
+
+// TestGELFAsyncOverflowDropNewest
+func TestGELFAsyncOverflowDropNewest(t *testing.T) {
+	gelf := &Gelf{
+		Config: Config{Async: true, QueueSize: 1, OverflowStrategy: OverflowDropNewest},
+		queue:  make(chan []byte, 1),
+	}
+
+	if _, err := gelf.writeAsync([]byte("first")); err != nil {
+		t.Fatalf("writeAsync failed: %s", err.Error())
+	}
+
+	if _, err := gelf.writeAsync([]byte("second")); err != nil {
+		t.Fatalf("writeAsync failed: %s", err.Error())
+	}
+
+	if stats := gelf.Stats(); stats.Dropped != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", stats.Dropped)
+	}
+
+	if queued := <-gelf.queue; string(queued) != "first" {
+		t.Errorf("queue held %q, want the first message preserved under DropNewest", queued)
+	}
+}
+
+// TestGELFAsyncOverflowDropOldest
+func TestGELFAsyncOverflowDropOldest(t *testing.T) {
+	gelf := &Gelf{
+		Config: Config{Async: true, QueueSize: 1, OverflowStrategy: OverflowDropOldest},
+		queue:  make(chan []byte, 1),
+	}
+
+	if _, err := gelf.writeAsync([]byte("first")); err != nil {
+		t.Fatalf("writeAsync failed: %s", err.Error())
+	}
+
+	if _, err := gelf.writeAsync([]byte("second")); err != nil {
+		t.Fatalf("writeAsync failed: %s", err.Error())
+	}
+
+	if stats := gelf.Stats(); stats.Dropped != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", stats.Dropped)
+	}
+
+	if queued := <-gelf.queue; string(queued) != "second" {
+		t.Errorf("queue held %q, want the newest message kept under DropOldest", queued)
+	}
+}
+
+// TestGELFAsyncOverflowBlock
+func TestGELFAsyncOverflowBlock(t *testing.T) {
+	gelf := &Gelf{
+		Config: Config{Async: true, QueueSize: 1, OverflowStrategy: OverflowBlock},
+		queue:  make(chan []byte, 1),
+	}
+
+	if _, err := gelf.writeAsync([]byte("first")); err != nil {
+		t.Fatalf("writeAsync failed: %s", err.Error())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		gelf.writeAsync([]byte("second"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("writeAsync returned before the queue had room, expected it to block under OverflowBlock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-gelf.queue // drain the first message, making room
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writeAsync did not unblock once the queue had room")
+	}
+}
+
+// TestGELFMTUDiscoveryCapsToConfigured verifies that DiscoverMTU never raises
+// MaxChunkSize above a value the caller explicitly configured
+func TestGELFMTUDiscoveryCapsToConfigured(t *testing.T) {
+	config := DefaultConfig("127.0.0.1")
+	config.Port = 1234
+	config.DiscoverMTU = true
+	config.MaxChunkSize = 100 // comfortably below any real interface MTU
+
+	gelf := New(config)
+	defer gelf.Close(time.Second)
+
+	if gelf.Config.MaxChunkSize != 100 {
+		t.Errorf("MaxChunkSize = %d after MTU discovery, want it capped at the configured 100", gelf.Config.MaxChunkSize)
+	}
+}
+
+// TestGELFMTUDiscoveryUncapped verifies that DiscoverMTU picks a sane chunk size from
+// the local interface when the caller leaves MaxChunkSize unset
+func TestGELFMTUDiscoveryUncapped(t *testing.T) {
+	config := DefaultConfig("127.0.0.1")
+	config.Port = 1234
+	config.DiscoverMTU = true
+	config.MaxChunkSize = 0
+
+	gelf := New(config)
+	defer gelf.Close(time.Second)
+
+	if gelf.Config.MaxChunkSize <= chunkHeaderSize {
+		t.Errorf("MaxChunkSize = %d after MTU discovery, want it greater than the %d-byte chunk header", gelf.Config.MaxChunkSize, chunkHeaderSize)
+	}
+}
+
+// BenchmarkGelfWrite measures the allocation and latency cost of Write on the pooled,
+// long-lived-connection path added to reuse the UDP socket and compression writers
+func BenchmarkGelfWrite(b *testing.B) {
+	host := "host.example.org"
+	msg := "Test Message"
+	packet := make([]byte, 2048)
+
+	testJSON, err := testMessage(msg, host)
+	if err != nil {
+		b.Fatalf("Getting Test Message failed: %s", err.Error())
+	}
+
+	config := DefaultConfig("127.0.0.1")
+	config.Port = 1234
+
+	gelf := New(config)
+	defer gelf.Close(time.Second)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := gelf.Write(testJSON); err != nil {
+			b.Fatalf("Write failed: %s", err.Error())
+		}
+
+		if _, _, err := testUDPlistener.ReadFromUDP(packet); err != nil {
+			b.Fatalf("Read failed: %s", err.Error())
+		}
+	}
+}
+
 // EOF
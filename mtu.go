@@ -0,0 +1,73 @@
+package gelf
+
+import (
+	"errors"
+	"net"
+)
+
+// udpHeaderOverhead is the IPv4 + UDP header size subtracted from a discovered or
+// probed path MTU to get a safe GELF chunk size
+const udpHeaderOverhead = 28
+
+// minChunkSize is the smallest chunk size MTU discovery will ever select, keeping
+// comfortable room above chunkHeaderSize even on a very constrained path
+const minChunkSize = chunkHeaderSize + 64
+
+// applyChunkSize runs MTU discovery for the destination, if enabled, and lowers
+// Config.MaxChunkSize to fit, never raising it above what was explicitly configured
+func (g *Gelf) applyChunkSize() {
+	if !g.Config.DiscoverMTU || g.udpConn == nil {
+		return
+	}
+
+	mtu, err := discoverMTU(g.udpConn)
+	if err != nil || mtu <= 0 {
+		return
+	}
+
+	chunkSize := mtu - udpHeaderOverhead
+	if chunkSize < minChunkSize {
+		chunkSize = minChunkSize
+	}
+
+	if g.Config.MaxChunkSize > 0 && chunkSize > g.Config.MaxChunkSize {
+		chunkSize = g.Config.MaxChunkSize
+	}
+
+	g.Config.MaxChunkSize = chunkSize
+}
+
+// probeMTU is the portable MTU discovery fallback. There is no cross-platform socket
+// option for path MTU, and actually sending GELF-shaped garbage datagrams at the
+// configured payload sizes would mean writing corrupt messages straight at the real
+// Graylog input, so instead it looks up the MTU of whichever local interface the
+// kernel chose to route conn's destination through. That only reflects the local
+// link, not a smaller MTU further along the path (e.g. a mid-route tunnel), but it
+// never puts anything on the wire toward the destination.
+func probeMTU(conn *net.UDPConn) (int, error) {
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || localAddr.IP == nil {
+		return 0, errors.New("gelf: could not determine local address for MTU probe")
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok && ipNet.IP.Equal(localAddr.IP) {
+				return iface.MTU, nil
+			}
+		}
+	}
+
+	return 0, errors.New("gelf: no local interface matched the UDP socket's address")
+}
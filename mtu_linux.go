@@ -0,0 +1,51 @@
+//go:build linux
+
+package gelf
+
+import (
+	"net"
+	"syscall"
+)
+
+// enablePathMTUDiscovery sets IP_MTU_DISCOVER so the kernel tracks the path MTU for this
+// socket, which is what makes IP_MTU below readable instead of just the local interface MTU
+func enablePathMTUDiscovery(conn *net.UDPConn) error {
+	sc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = sc.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
+
+// discoverMTU asks the kernel for the path MTU of the connected UDP socket via IP_MTU,
+// falling back to probeMTU's local-interface lookup if the kernel can't report it yet
+func discoverMTU(conn *net.UDPConn) (int, error) {
+	sc, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var mtu int
+	var sockErr error
+
+	err = sc.Control(func(fd uintptr) {
+		mtu, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if sockErr != nil || mtu <= 0 {
+		return probeMTU(conn)
+	}
+
+	return mtu, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package gelf
+
+import "net"
+
+// enablePathMTUDiscovery has no portable equivalent of Linux's IP_MTU_DISCOVER, so
+// discoverMTU relies entirely on probeMTU's local-interface lookup on this platform
+func enablePathMTUDiscovery(conn *net.UDPConn) error {
+	return nil
+}
+
+// discoverMTU falls back to probeMTU's local-interface lookup, since this platform has
+// no socket option exposing the kernel's view of the path MTU
+func discoverMTU(conn *net.UDPConn) (int, error) {
+	return probeMTU(conn)
+}
@@ -0,0 +1,127 @@
+package gelf
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// udpResolveInterval controls how often the persistent UDP connection is re-dialed,
+// picking up changes to the resolved destination address (e.g. DNS-based failover)
+const udpResolveInterval = 30 * time.Second
+
+// addr returns the configured host:port pair used to reach the GELF input
+func (g *Gelf) addr() string {
+	return g.Config.Host + ":" + strconv.Itoa(g.Config.Port)
+}
+
+// dialUDP resolves the configured host:port and dials a fresh UDP socket to it
+func (g *Gelf) dialUDP() (*net.UDPConn, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", g.addr())
+	if err != nil {
+		return nil, err
+	}
+
+	return net.DialUDP("udp", nil, udpAddr)
+}
+
+// watchUDPAddr periodically re-dials the UDP destination so a change in DNS resolution
+// is picked up without requiring a new Gelf, until Close shuts it down
+func (g *Gelf) watchUDPAddr() {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(udpResolveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			conn, err := g.dialUDP()
+			if err != nil {
+				continue
+			}
+
+			g.mu.Lock()
+			old := g.udpConn
+			g.udpConn = conn
+			if g.Config.DiscoverMTU {
+				_ = enablePathMTUDiscovery(conn)
+				g.applyChunkSize()
+			}
+			g.mu.Unlock()
+
+			if old != nil {
+				old.Close()
+			}
+
+		case <-g.closed:
+			return
+		}
+	}
+}
+
+// send transmits a single UDP datagram over the long-lived connection, dialing one
+// lazily if it is not yet established
+func (g *Gelf) send(b []byte) (int, error) {
+	g.mu.Lock()
+	conn := g.udpConn
+	g.mu.Unlock()
+
+	if conn == nil {
+		var err error
+		conn, err = g.dialUDP()
+		if err != nil {
+			return 0, err
+		}
+
+		g.mu.Lock()
+		g.udpConn = conn
+		g.mu.Unlock()
+	}
+
+	return conn.Write(b)
+}
+
+// sendTCP frames b with the GELF-over-TCP \x00 terminator and writes it to a persistent
+// connection, redialing up to Config.MaxReconnect times on transient network errors
+func (g *Gelf) sendTCP(b []byte) (int, error) {
+	framed := make([]byte, len(b)+1)
+	copy(framed, b)
+	framed[len(b)] = '\x00'
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var lastErr error
+
+	for attempt := 0; attempt <= g.Config.MaxReconnect; attempt++ {
+		if attempt > 0 {
+			time.Sleep(g.Config.ReconnectDelay)
+		}
+
+		if g.tcpConn == nil {
+			conn, err := net.Dial("tcp", g.addr())
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			g.tcpConn = conn
+
+			if attempt > 0 {
+				g.reconnects.Add(1)
+			}
+		}
+
+		n, err := g.tcpConn.Write(framed)
+		if err != nil {
+			g.tcpConn.Close()
+			g.tcpConn = nil
+			lastErr = err
+			continue
+		}
+
+		return n, nil
+	}
+
+	return 0, lastErr
+}